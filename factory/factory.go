@@ -4,9 +4,35 @@ import (
 	"fmt"
 	"reflect"
 	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
 )
 
 var factories = make(map[reflect.Type]map[string]interface{})
+var traits = make(map[reflect.Type]map[string]map[string]interface{})
+var funcRegistry = make(map[string]interface{})
+var typeRegistry = make(map[string]reflect.Type)
+
+type StructValidator interface {
+	Validate(interface{}) error
+}
+
+type defaultValidator struct {
+	validate *validator.Validate
+}
+
+func (d *defaultValidator) Validate(i interface{}) error {
+	return d.validate.Struct(i)
+}
+
+var structValidator StructValidator = &defaultValidator{validate: validator.New()}
+
+func SetValidator(v StructValidator) {
+	structValidator = v
+}
 
 func checkParams(v reflect.Value, params map[string]interface{}) error {
 	for field, value := range params {
@@ -14,6 +40,9 @@ func checkParams(v reflect.Value, params map[string]interface{}) error {
 		if !fieldV.IsValid() {
 			return errors.New(fmt.Sprintf("Invalid field %s.", field))
 		}
+		if _, ok := value.(factoryProvider); ok {
+			continue
+		}
 		valueV := reflect.ValueOf(value)
 		valueT := valueV.Type()
 		if valueV.Kind() == reflect.Func {
@@ -33,9 +62,17 @@ func checkParams(v reflect.Value, params map[string]interface{}) error {
 	return nil
 }
 
-func execParams(v reflect.Value, params map[string]interface{}) {
+func execParams(v reflect.Value, params map[string]interface{}) error {
 	for field, value := range params {
 		fieldV := v.FieldByName(field)
+		if provider, ok := value.(factoryProvider); ok {
+			valueV, err := provider.provide(fieldV.Type(), &buildCtx{ownerType: v.Type(), field: field})
+			if err != nil {
+				return err
+			}
+			fieldV.Set(valueV)
+			continue
+		}
 		valueV := reflect.ValueOf(value)
 		if valueV.Kind() == reflect.Func {
 			evaluated := valueV.Call([]reflect.Value{})
@@ -43,6 +80,71 @@ func execParams(v reflect.Value, params map[string]interface{}) {
 		}
 		fieldV.Set(valueV)
 	}
+	return nil
+}
+
+// cloneParams returns a params map independent of params, so that writing
+// overrides into the result can never poison a registered factory. Nested
+// maps, slices, and pointer-typed values are deep-copied; func() providers
+// and factoryProvider values are left untouched since they carry their own
+// behavior rather than data. A struct value, including one reached through a
+// pointer, is copied as a whole without descending into its fields, so maps
+// and slices held in struct fields are still shared.
+func cloneParams(params map[string]interface{}) map[string]interface{} {
+	cloned := make(map[string]interface{}, len(params))
+	for key, val := range params {
+		cloned[key] = cloneValue(val)
+	}
+	return cloned
+}
+
+func cloneValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch value.(type) {
+	case factoryProvider:
+		return value
+	}
+	valueV := reflect.ValueOf(value)
+	clonedOf := func(elem interface{}, elemT reflect.Type) reflect.Value {
+		if cloned := cloneValue(elem); cloned != nil {
+			return reflect.ValueOf(cloned)
+		}
+		return reflect.Zero(elemT)
+	}
+	switch valueV.Kind() {
+	case reflect.Func:
+		return value
+	case reflect.Map:
+		if valueV.IsNil() {
+			return value
+		}
+		clonedV := reflect.MakeMapWithSize(valueV.Type(), valueV.Len())
+		iter := valueV.MapRange()
+		for iter.Next() {
+			clonedV.SetMapIndex(iter.Key(), clonedOf(iter.Value().Interface(), valueV.Type().Elem()))
+		}
+		return clonedV.Interface()
+	case reflect.Slice:
+		if valueV.IsNil() {
+			return value
+		}
+		clonedV := reflect.MakeSlice(valueV.Type(), valueV.Len(), valueV.Len())
+		for idx := 0; idx < valueV.Len(); idx++ {
+			clonedV.Index(idx).Set(clonedOf(valueV.Index(idx).Interface(), valueV.Type().Elem()))
+		}
+		return clonedV.Interface()
+	case reflect.Ptr:
+		if valueV.IsNil() {
+			return value
+		}
+		clonedV := reflect.New(valueV.Type().Elem())
+		clonedV.Elem().Set(clonedOf(valueV.Elem().Interface(), valueV.Type().Elem()))
+		return clonedV.Interface()
+	default:
+		return value
+	}
 }
 
 func parseArgs(i interface{}, options []interface{}) (reflect.Value, map[string]interface{}, error) {
@@ -50,30 +152,41 @@ func parseArgs(i interface{}, options []interface{}) (reflect.Value, map[string]
 	if p.Kind() != reflect.Ptr {
 		return reflect.Value{}, nil, errors.New("Not a pointer.")
 	}
-	
+
 	v := reflect.Indirect(p)
 	if v.Kind() != reflect.Struct {
 		return reflect.Value{}, nil, errors.New("Does not point to a struct.")
 	}
-	
-	if len(options) > 1 {
-		return reflect.Value{}, nil, errors.New("Too many options.")
-	}
-	
-	defaultParams := factories[v.Type()]
-	if defaultParams == nil {
-		defaultParams = make(map[string]interface{})
-	}
-	
-	if len(options) == 1 {
-		params, ok := options[0].(map[string]interface{})
-		if !ok {
-			return reflect.Value{}, nil, errors.New("Options are not map[string]interface{}.")
+	t := v.Type()
+
+	defaultParams := cloneParams(factories[t])
+
+	var overrides map[string]interface{}
+	for _, option := range options {
+		switch o := option.(type) {
+		case string:
+			traitParams, ok := traits[t][o]
+			if !ok {
+				return reflect.Value{}, nil, errors.New(fmt.Sprintf("Unknown trait %q.", o))
+			}
+			for key, val := range traitParams {
+				defaultParams[key] = cloneValue(val)
+			}
+		case map[string]interface{}:
+			if overrides != nil {
+				return reflect.Value{}, nil, errors.New("Too many options.")
+			}
+			overrides = o
+		default:
+			return reflect.Value{}, nil, errors.New("Options must be a trait name (string) or map[string]interface{}.")
 		}
-		if err := checkParams(v, params); err != nil {
+	}
+
+	if overrides != nil {
+		if err := checkParams(v, overrides); err != nil {
 			return reflect.Value{}, nil, err
 		}
-		for key, val := range params {
+		for key, val := range overrides {
 			defaultParams[key] = val
 		}
 	}
@@ -85,7 +198,12 @@ func Build(i interface{}, options ...interface{}) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	execParams(v, params)
+	if err := execParams(v, params); err != nil {
+		return nil, err
+	}
+	if err := structValidator.Validate(i); err != nil {
+		return nil, err
+	}
 	return i, nil
 }
 
@@ -97,29 +215,30 @@ func BuildMany(i interface{}, n int, options ...interface{}) ([]interface{}, err
 	arr := make([]interface{}, n)
 	for k, _ := range arr {
 		arr[k] = reflect.New(v.Type()).Interface()
-		execParams(reflect.Indirect(reflect.ValueOf(arr[k])), params)
+		if err := execParams(reflect.Indirect(reflect.ValueOf(arr[k])), cloneParams(params)); err != nil {
+			return nil, err
+		}
+		if err := structValidator.Validate(arr[k]); err != nil {
+			return nil, err
+		}
 	}
 	return arr, nil
 }
 
 func MustBuildMany(i interface{}, n int, options ...interface{}) []interface{} {
-	v, params, err := parseArgs(i, options)
+	arr, err := BuildMany(i, n, options...)
 	if err != nil {
-		return []interface{}{}
-	}
-	arr := make([]interface{}, n)
-	for k, _ := range arr {
-		arr[k] = reflect.New(v.Type()).Interface()
-		execParams(reflect.Indirect(reflect.ValueOf(arr[k])), params)
+		panic(err)
 	}
 	return arr
 }
 
 func MustBuild(i interface{}, options ...interface{}) interface{} {
-	if v, params, err := parseArgs(i, options); err == nil {
-		execParams(v, params)
+	built, err := Build(i, options...)
+	if err != nil {
+		panic(err)
 	}
-	return i
+	return built
 }
 
 func Register(i interface{}, params map[string]interface{}) error {
@@ -133,3 +252,247 @@ func Register(i interface{}, params map[string]interface{}) error {
 	factories[v.Type()] = params
 	return nil
 }
+
+func RegisterTrait(i interface{}, name string, params map[string]interface{}) error {
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Struct {
+		return errors.New("Not a struct.")
+	}
+	if err := checkParams(v, params); err != nil {
+		return err
+	}
+	t := v.Type()
+	if traits[t] == nil {
+		traits[t] = make(map[string]map[string]interface{})
+	}
+	traits[t][name] = params
+	return nil
+}
+
+func RegisterFunc(name string, fn interface{}) {
+	funcRegistry[name] = fn
+}
+
+func RegisterType(i interface{}) error {
+	v := reflect.ValueOf(i)
+	if v.Kind() == reflect.Ptr {
+		v = reflect.Indirect(v)
+	}
+	if v.Kind() != reflect.Struct {
+		return errors.New("Not a struct.")
+	}
+
+	t := v.Type()
+	params := make(map[string]interface{})
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("factory")
+		if !ok {
+			continue
+		}
+		val, err := parseFactoryTag(tag, field)
+		if err != nil {
+			return err
+		}
+		params[field.Name] = val
+	}
+
+	if err := checkParams(v, params); err != nil {
+		return err
+	}
+	typeRegistry[t.Name()] = t
+	factories[t] = params
+	return nil
+}
+
+func parseFactoryTag(tag string, field reflect.StructField) (interface{}, error) {
+	directive, value, hasValue := strings.Cut(tag, "=")
+	switch directive {
+	case "name":
+		if !hasValue {
+			return nil, errors.New(fmt.Sprintf("factory tag %q for field %s requires a value.", tag, field.Name))
+		}
+		return convertLiteral(value, field.Type)
+	case "func":
+		if !hasValue {
+			return nil, errors.New(fmt.Sprintf("factory tag %q for field %s requires a function name.", tag, field.Name))
+		}
+		fn, ok := funcRegistry[value]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("No function registered under name %q.", value))
+		}
+		return fn, nil
+	case "sequence":
+		return newSequenceFunc(field.Type), nil
+	case "assoc":
+		if !hasValue {
+			return nil, errors.New(fmt.Sprintf("factory tag %q for field %s requires a type name.", tag, field.Name))
+		}
+		return newAssocProvider(value)
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown factory tag directive %q for field %s.", directive, field.Name))
+	}
+}
+
+func convertLiteral(value string, t reflect.Type) (interface{}, error) {
+	if t == reflect.TypeOf(time.Time{}) {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Value %q is not a valid RFC3339 timestamp.", value))
+		}
+		return parsed, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Value %q is not a valid bool.", value))
+		}
+		return parsed, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Value %q is not a valid int.", value))
+		}
+		return reflect.ValueOf(parsed).Convert(t).Interface(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Value %q is not a valid uint.", value))
+		}
+		return reflect.ValueOf(parsed).Convert(t).Interface(), nil
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Value %q is not a valid float.", value))
+		}
+		return reflect.ValueOf(parsed).Convert(t).Interface(), nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Field of kind %s cannot take a literal factory value.", t.Kind()))
+	}
+}
+
+func newSequenceFunc(fieldType reflect.Type) interface{} {
+	n := 0
+	fnType := reflect.FuncOf(nil, []reflect.Type{fieldType}, false)
+	fn := reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		n++
+		return []reflect.Value{reflect.ValueOf(n).Convert(fieldType)}
+	})
+	return fn.Interface()
+}
+
+// buildCtx carries the state a factoryProvider needs while a single field is
+// being filled in, such as which struct type and field it belongs to.
+type buildCtx struct {
+	ownerType reflect.Type
+	field     string
+}
+
+// factoryProvider is implemented by dynamic field values produced by Sequence,
+// Lazy, and Assoc. execParams and checkParams recognize it in addition to the
+// plain func() T mechanism.
+type factoryProvider interface {
+	provide(fieldType reflect.Type, ctx *buildCtx) (reflect.Value, error)
+}
+
+var sequenceCounters = make(map[reflect.Type]map[string]int)
+
+func nextSequence(ownerType reflect.Type, field string) int {
+	counters, ok := sequenceCounters[ownerType]
+	if !ok {
+		counters = make(map[string]int)
+		sequenceCounters[ownerType] = counters
+	}
+	counters[field]++
+	return counters[field]
+}
+
+type sequenceProvider struct {
+	fn func(n int) interface{}
+}
+
+func (s *sequenceProvider) provide(fieldType reflect.Type, ctx *buildCtx) (reflect.Value, error) {
+	valueV := reflect.ValueOf(s.fn(nextSequence(ctx.ownerType, ctx.field)))
+	if !valueV.Type().AssignableTo(fieldType) {
+		return reflect.Value{}, errors.New(fmt.Sprintf("Sequence value %+v for field %s is invalid.", valueV, ctx.field))
+	}
+	return valueV, nil
+}
+
+func Sequence(fn func(n int) interface{}) interface{} {
+	return &sequenceProvider{fn: fn}
+}
+
+type lazyProvider struct {
+	fn func() interface{}
+}
+
+func (l *lazyProvider) provide(fieldType reflect.Type, ctx *buildCtx) (reflect.Value, error) {
+	valueV := reflect.ValueOf(l.fn())
+	if !valueV.Type().AssignableTo(fieldType) {
+		return reflect.Value{}, errors.New(fmt.Sprintf("Lazy value %+v for field %s is invalid.", valueV, ctx.field))
+	}
+	return valueV, nil
+}
+
+func Lazy(fn func() interface{}) interface{} {
+	return &lazyProvider{fn: fn}
+}
+
+type assocProvider struct {
+	i       interface{}
+	options []interface{}
+}
+
+func (a *assocProvider) provide(fieldType reflect.Type, ctx *buildCtx) (reflect.Value, error) {
+	built, err := Build(a.i, a.options...)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	builtV := reflect.ValueOf(built)
+	if fieldType.Kind() != reflect.Ptr {
+		builtV = reflect.Indirect(builtV)
+	}
+	if !builtV.Type().AssignableTo(fieldType) {
+		return reflect.Value{}, errors.New(fmt.Sprintf("Associated value %+v for field %s is invalid.", builtV, ctx.field))
+	}
+	return builtV, nil
+}
+
+func Assoc(i interface{}, options ...interface{}) interface{} {
+	return &assocProvider{i: i, options: options}
+}
+
+type tagAssocProvider struct {
+	assocType reflect.Type
+}
+
+func (a *tagAssocProvider) provide(fieldType reflect.Type, ctx *buildCtx) (reflect.Value, error) {
+	built, err := Build(reflect.New(a.assocType).Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	result := reflect.ValueOf(built)
+	if fieldType.Kind() != reflect.Ptr {
+		result = reflect.Indirect(result)
+	}
+	if !result.Type().AssignableTo(fieldType) {
+		return reflect.Value{}, errors.New(fmt.Sprintf("Associated value %+v for field %s is invalid.", result, ctx.field))
+	}
+	return result, nil
+}
+
+func newAssocProvider(typeName string) (interface{}, error) {
+	assocT, ok := typeRegistry[typeName]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No type registered under name %q.", typeName))
+	}
+	return &tagAssocProvider{assocType: assocT}, nil
+}
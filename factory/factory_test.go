@@ -0,0 +1,82 @@
+package factory
+
+import (
+	"reflect"
+	"testing"
+)
+
+type widget struct {
+	Tags []string
+	ID   int
+}
+
+func resetWidgetRegistry() {
+	delete(factories, reflect.TypeOf(widget{}))
+	delete(traits, reflect.TypeOf(widget{}))
+}
+
+func TestBuildDoesNotMutateRegisteredDefaults(t *testing.T) {
+	resetWidgetRegistry()
+	if err := Register(widget{}, map[string]interface{}{"Tags": []string{"a", "b"}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	built, err := Build(&widget{}, map[string]interface{}{"ID": 1})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	built.(*widget).Tags[0] = "mutated"
+
+	second, err := Build(&widget{})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if got := second.(*widget).Tags[0]; got != "a" {
+		t.Fatalf("second Build saw a poisoned default: got %q, want %q", got, "a")
+	}
+}
+
+func TestBuildManyInvokesProviderPerElement(t *testing.T) {
+	resetWidgetRegistry()
+	n := 0
+	if err := Register(widget{}, map[string]interface{}{
+		"ID": func() int { n++; return n },
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	arr, err := BuildMany(&widget{}, 2)
+	if err != nil {
+		t.Fatalf("BuildMany: %v", err)
+	}
+	if first, second := arr[0].(*widget).ID, arr[1].(*widget).ID; first == second {
+		t.Fatalf("BuildMany gave every element the same provider result: %d", first)
+	}
+}
+
+func TestBuildManyDoesNotShareTraitValuesAcrossElements(t *testing.T) {
+	resetWidgetRegistry()
+	if err := Register(widget{}, map[string]interface{}{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := RegisterTrait(widget{}, "special", map[string]interface{}{"Tags": []string{"a", "b"}}); err != nil {
+		t.Fatalf("RegisterTrait: %v", err)
+	}
+
+	arr, err := BuildMany(&widget{}, 2, "special")
+	if err != nil {
+		t.Fatalf("BuildMany: %v", err)
+	}
+	arr[0].(*widget).Tags[0] = "mutated"
+	if got := arr[1].(*widget).Tags[0]; got != "a" {
+		t.Fatalf("BuildMany shared the trait's slice across elements: got %q, want %q", got, "a")
+	}
+
+	rebuilt, err := Build(&widget{}, "special")
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if got := rebuilt.(*widget).Tags[0]; got != "a" {
+		t.Fatalf("trait mutation leaked into the registry: got %q, want %q", got, "a")
+	}
+}
@@ -0,0 +1,80 @@
+package factory
+
+import "testing"
+
+type taggedWithUnexported struct {
+	Name   string `factory:"name=John"`
+	hidden string `factory:"name=oops"`
+}
+
+func TestRegisterTypeSkipsUnexportedFields(t *testing.T) {
+	if err := RegisterType(taggedWithUnexported{}); err != nil {
+		t.Fatalf("RegisterType: %v", err)
+	}
+	built, err := Build(&taggedWithUnexported{})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if got := built.(*taggedWithUnexported).Name; got != "John" {
+		t.Fatalf("Name = %q, want John", got)
+	}
+}
+
+type tagAssocTarget struct {
+	Value int
+}
+
+type tagAssocHolder struct {
+	Target tagAssocTarget `factory:"assoc=tagAssocTarget"`
+}
+
+func TestTagAssocPropagatesNestedErrors(t *testing.T) {
+	if err := RegisterType(tagAssocTarget{}); err != nil {
+		t.Fatalf("RegisterType(tagAssocTarget): %v", err)
+	}
+	if err := Register(tagAssocTarget{}, map[string]interface{}{
+		"Value": Lazy(func() interface{} { return "not-an-int" }),
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := RegisterType(tagAssocHolder{}); err != nil {
+		t.Fatalf("RegisterType(tagAssocHolder): %v", err)
+	}
+	if _, err := Build(&tagAssocHolder{}); err == nil {
+		t.Fatalf("expected Build to surface the nested provider type mismatch, got nil error")
+	}
+}
+
+type tagAssocMutTarget struct {
+	Tags []string
+}
+
+type tagAssocMutHolder struct {
+	Target tagAssocMutTarget `factory:"assoc=tagAssocMutTarget"`
+}
+
+func TestTagAssocDoesNotMutateRegisteredDefaults(t *testing.T) {
+	if err := RegisterType(tagAssocMutTarget{}); err != nil {
+		t.Fatalf("RegisterType(tagAssocMutTarget): %v", err)
+	}
+	if err := Register(tagAssocMutTarget{}, map[string]interface{}{"Tags": []string{"a", "b"}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := RegisterType(tagAssocMutHolder{}); err != nil {
+		t.Fatalf("RegisterType(tagAssocMutHolder): %v", err)
+	}
+
+	built, err := Build(&tagAssocMutHolder{})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	built.(*tagAssocMutHolder).Target.Tags[0] = "mutated"
+
+	second, err := Build(&tagAssocMutHolder{})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if got := second.(*tagAssocMutHolder).Target.Tags[0]; got != "a" {
+		t.Fatalf("tag-based assoc saw a poisoned default: got %q, want %q", got, "a")
+	}
+}
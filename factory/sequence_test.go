@@ -0,0 +1,49 @@
+package factory
+
+import "testing"
+
+type childRecord struct {
+	Label string
+}
+
+type parentRecord struct {
+	Seq   int
+	Label string
+	Child childRecord
+}
+
+func TestSequenceLazyAssocRoundTrip(t *testing.T) {
+	if err := Register(childRecord{}, map[string]interface{}{"Label": "child-default"}); err != nil {
+		t.Fatalf("Register(childRecord): %v", err)
+	}
+	if err := Register(parentRecord{}, map[string]interface{}{
+		"Seq":   Sequence(func(n int) interface{} { return n }),
+		"Label": Lazy(func() interface{} { return "lazy-value" }),
+		"Child": Assoc(&childRecord{}),
+	}); err != nil {
+		t.Fatalf("Register(parentRecord): %v", err)
+	}
+
+	first, err := Build(&parentRecord{})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	firstSeq := first.(*parentRecord).Seq
+	if firstSeq == 0 {
+		t.Fatalf("Seq = 0, want a positive sequence value")
+	}
+	if got := first.(*parentRecord).Label; got != "lazy-value" {
+		t.Fatalf("Label = %q, want lazy-value", got)
+	}
+	if got := first.(*parentRecord).Child.Label; got != "child-default" {
+		t.Fatalf("Child.Label = %q, want child-default", got)
+	}
+
+	second, err := Build(&parentRecord{})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if got := second.(*parentRecord).Seq; got != firstSeq+1 {
+		t.Fatalf("second Seq = %d, want %d", got, firstSeq+1)
+	}
+}
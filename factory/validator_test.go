@@ -0,0 +1,40 @@
+package factory
+
+import "testing"
+
+type validatedRecord struct {
+	Name string `validate:"required"`
+}
+
+func TestBuildReturnsValidationError(t *testing.T) {
+	if _, err := Build(&validatedRecord{}); err == nil {
+		t.Fatalf("expected Build to fail validation for an empty required field")
+	}
+	if _, err := Build(&validatedRecord{}, map[string]interface{}{"Name": "ok"}); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+}
+
+func TestBuildManyReturnsValidationError(t *testing.T) {
+	if _, err := BuildMany(&validatedRecord{}, 2); err == nil {
+		t.Fatalf("expected BuildMany to fail validation for an empty required field")
+	}
+}
+
+func TestMustBuildPanicsOnValidationError(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected MustBuild to panic on a validation error")
+		}
+	}()
+	MustBuild(&validatedRecord{})
+}
+
+func TestMustBuildManyPanicsOnValidationError(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected MustBuildMany to panic on a validation error")
+		}
+	}()
+	MustBuildMany(&validatedRecord{}, 2)
+}